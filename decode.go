@@ -0,0 +1,230 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal parses VMX-encoded data and stores the result in the struct
+// pointed to by v.
+//
+// Unmarshal reverses Marshal: it tokenizes data into "key = \"value\""
+// pairs, skipping blank lines and comments starting with "#", and then uses
+// the `vmx:"..."` struct tags on v to populate fields, including embedded
+// structs (matched by dotted-prefix expansion) and slice-of-struct fields
+// (matched by a numeric suffix on the tag prefix, e.g. ethernet0,
+// ethernet1, ...). A key that is absent from data leaves the corresponding
+// field at its zero value. Fields tagged `vmx:"-"` are skipped.
+func Unmarshal(data []byte, v interface{}) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return fmt.Errorf("vmx: Unmarshal requires a non-nil pointer to a struct, got %s", val.Kind())
+	}
+	val = val.Elem()
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("vmx: Unmarshal requires a pointer to a struct, got pointer to %s", val.Kind())
+	}
+
+	fields, err := tokenize(data)
+	if err != nil {
+		return err
+	}
+	return unmarshalStruct(fields, "", val)
+}
+
+// tokenize splits VMX-encoded data into a map of dotted key to unquoted
+// value, ignoring blank lines and "#"-prefixed comments.
+func tokenize(data []byte) (map[string]string, error) {
+	fields := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf("vmx: invalid line: %q", line)
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+
+		fields[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// unmarshalStruct populates the fields of val from fields, joining each
+// field's tag name to prefix with a dot when prefix is non-empty.
+func unmarshalStruct(fields map[string]string, prefix string, val reflect.Value) error {
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		rawTag := field.Tag.Get("vmx")
+		if rawTag == "" {
+			continue
+		}
+
+		name, _, err := parseTag(`vmx:"` + rawTag + `"`)
+		if err != nil {
+			return err
+		}
+		if name == "-" {
+			continue
+		}
+
+		// A tag name of ":" marks a slice whose elements are addressed as
+		// controller units (e.g. scsi0:0, scsi0:1); see marshalStruct.
+		unitIndexed := name == ":"
+
+		var key string
+		switch {
+		case unitIndexed:
+			key = prefix
+		case prefix != "":
+			key = prefix + "." + name
+		default:
+			key = name
+		}
+
+		fv := val.Field(i)
+
+		if u, ok := asUnmarshaler(fv); ok {
+			if err := u.UnmarshalVMX(key, fields); err != nil {
+				return fmt.Errorf("vmx: field %s: %s", key, err)
+			}
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			if err := unmarshalStruct(fields, key, fv); err != nil {
+				return err
+			}
+		case reflect.Slice:
+			sep := ""
+			if unitIndexed {
+				sep = ":"
+			}
+			if err := unmarshalSlice(fields, key, sep, fv); err != nil {
+				return err
+			}
+		default:
+			value, ok := fields[key]
+			if !ok {
+				continue
+			}
+			if err := setField(fv, value); err != nil {
+				return fmt.Errorf("vmx: field %s: %s", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// unmarshalSlice populates fv, a slice of structs or struct pointers, from
+// the fields whose keys match prefix and sep followed by a numeric index,
+// e.g. prefix "ethernet" and sep "" matches "ethernet0", "ethernet1", ...,
+// and prefix "scsi0" and sep ":" matches "scsi0:0", "scsi0:1", .... The
+// index may be followed by either a "." (a regular subfield, e.g.
+// "ethernet0.present") or a ":" (a unit-indexed sub-slice, e.g.
+// "ide0:0.present" for an element struct, like IDEController, whose only
+// fields are themselves unit-indexed). A pointer element type is
+// allocated for each index, mirroring the pointer dereferencing
+// marshalStruct performs when encoding the same slice.
+func unmarshalSlice(fields map[string]string, prefix, sep string, fv reflect.Value) error {
+	elemType := fv.Type().Elem()
+	structType := elemType
+	ptrElem := structType.Kind() == reflect.Ptr
+	if ptrElem {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("vmx: unsupported slice element kind %s", elemType.Kind())
+	}
+
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + regexp.QuoteMeta(sep) + `(\d+)([:.].*)?$`)
+
+	max := -1
+	for key := range fields {
+		m := re.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		idx, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		if idx > max {
+			max = idx
+		}
+	}
+	if max < 0 {
+		return nil
+	}
+
+	slice := reflect.MakeSlice(fv.Type(), max+1, max+1)
+	for idx := 0; idx <= max; idx++ {
+		elemPtr := reflect.New(structType)
+		if err := unmarshalStruct(fields, fmt.Sprintf("%s%s%d", prefix, sep, idx), elemPtr.Elem()); err != nil {
+			return err
+		}
+		if ptrElem {
+			slice.Index(idx).Set(elemPtr)
+		} else {
+			slice.Index(idx).Set(elemPtr.Elem())
+		}
+	}
+	fv.Set(slice)
+
+	return nil
+}
+
+// setField converts value to fv's underlying bool, string, or integer kind
+// and assigns it.
+func setField(fv reflect.Value, value string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported field kind %s", fv.Kind())
+	}
+	return nil
+}
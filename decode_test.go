@@ -0,0 +1,172 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import "testing"
+
+func TestUnmarshal(t *testing.T) {
+	type VM struct {
+		Encoding     string `vmx:".encoding"`
+		Annotation   string `vmx:"annotation"`
+		Hwversion    uint8  `vmx:"virtualHW.version"`
+		HwProdCompat string `vmx:"virtualHW.productCompatibility"`
+		Memsize      uint   `vmx:"memsize"`
+		Numvcpus     uint   `vmx:"numvcpus"`
+		MemHotAdd    bool   `vmx:"mem.hotadd"`
+		DisplayName  string `vmx:"displayName"`
+		GuestOS      string `vmx:"guestOS"`
+		Autoanswer   bool   `vmx:"msg.autoAnswer"`
+	}
+
+	data := `# this is a comment
+.encoding = "utf-8"
+annotation = "Test VM"
+virtualHW.version = "10"
+virtualHW.productCompatibility = "hosted"
+memsize = "1024"
+numvcpus = "2"
+mem.hotadd = "false"
+displayName = "test"
+guestOS = "other3xlinux-64"
+msg.autoAnswer = "true"
+`
+
+	vm := new(VM)
+	err := Unmarshal([]byte(data), vm)
+	ok(t, err)
+
+	equals(t, "utf-8", vm.Encoding)
+	equals(t, "Test VM", vm.Annotation)
+	equals(t, uint8(10), vm.Hwversion)
+	equals(t, "hosted", vm.HwProdCompat)
+	equals(t, uint(1024), vm.Memsize)
+	equals(t, uint(2), vm.Numvcpus)
+	equals(t, false, vm.MemHotAdd)
+	equals(t, "test", vm.DisplayName)
+	equals(t, "other3xlinux-64", vm.GuestOS)
+	equals(t, true, vm.Autoanswer)
+}
+
+func TestUnmarshalEmbedded(t *testing.T) {
+	type Vhardware struct {
+		Version string `vmx:"version"`
+		Compat  string `vmx:"productCompatibility"`
+	}
+
+	type VM struct {
+		Annotation string    `vmx:"annotation"`
+		Vhardware  Vhardware `vmx:"virtualHW"`
+		Memsize    uint      `vmx:"memsize"`
+	}
+
+	data := `annotation = "Test VM"
+virtualHW.version = "10"
+virtualHW.productCompatibility = "hosted"
+memsize = "1024"
+`
+
+	vm := new(VM)
+	err := Unmarshal([]byte(data), vm)
+	ok(t, err)
+
+	equals(t, "Test VM", vm.Annotation)
+	equals(t, "10", vm.Vhardware.Version)
+	equals(t, "hosted", vm.Vhardware.Compat)
+	equals(t, uint(1024), vm.Memsize)
+}
+
+func TestUnmarshalArray(t *testing.T) {
+	type Ethernet struct {
+		StartConnected       bool   `vmx:"startConnected"`
+		Present              bool   `vmx:"present"`
+		ConnectionType       string `vmx:"connectionType"`
+		LinkStatePropagation bool   `vmx:"linkStatePropagation.enable,omitempty"`
+	}
+
+	type VM struct {
+		Annotation string     `vmx:"annotation"`
+		Ethernet   []Ethernet `vmx:"ethernet"`
+	}
+
+	data := `annotation = "Test VM"
+ethernet0.startConnected = "true"
+ethernet0.present = "true"
+ethernet0.connectionType = "bridged"
+ethernet0.linkStatePropagation.enable = "true"
+ethernet1.startConnected = "true"
+ethernet1.present = "true"
+ethernet1.connectionType = "nat"
+`
+
+	vm := new(VM)
+	err := Unmarshal([]byte(data), vm)
+	ok(t, err)
+
+	equals(t, "Test VM", vm.Annotation)
+	equals(t, 2, len(vm.Ethernet))
+	equals(t, Ethernet{StartConnected: true, Present: true, ConnectionType: "bridged", LinkStatePropagation: true}, vm.Ethernet[0])
+	equals(t, Ethernet{StartConnected: true, Present: true, ConnectionType: "nat"}, vm.Ethernet[1])
+}
+
+func TestUnmarshalPointerSlice(t *testing.T) {
+	type Ethernet struct {
+		Present        bool   `vmx:"present"`
+		ConnectionType string `vmx:"connectionType"`
+	}
+
+	type VM struct {
+		Annotation string      `vmx:"annotation"`
+		Ethernet   []*Ethernet `vmx:"ethernet"`
+	}
+
+	data := `annotation = "Test VM"
+ethernet0.present = "true"
+ethernet0.connectionType = "bridged"
+ethernet1.present = "true"
+ethernet1.connectionType = "nat"
+`
+
+	vm := new(VM)
+	err := Unmarshal([]byte(data), vm)
+	ok(t, err)
+
+	equals(t, "Test VM", vm.Annotation)
+	equals(t, 2, len(vm.Ethernet))
+	equals(t, &Ethernet{Present: true, ConnectionType: "bridged"}, vm.Ethernet[0])
+	equals(t, &Ethernet{Present: true, ConnectionType: "nat"}, vm.Ethernet[1])
+}
+
+func TestUnmarshalIDEController(t *testing.T) {
+	vm := new(VirtualMachine)
+	controller := vm.AddIDEController()
+	ok(t, vm.AttachIDEDisk(controller, 0, "cdrom.iso"))
+
+	data, err := Marshal(vm)
+	ok(t, err)
+
+	decoded := new(VirtualMachine)
+	ok(t, Unmarshal(data, decoded))
+	equals(t, vm, decoded)
+	equals(t, 1, len(decoded.IDE))
+	equals(t, 1, len(decoded.IDE[0].Disk))
+	equals(t, "cdrom.iso", decoded.IDE[0].Disk[0].FileName)
+}
+
+func TestUnmarshalSkipsUnknownField(t *testing.T) {
+	type VM struct {
+		Annotation string `vmx:"annotation"`
+		Internal   string `vmx:"-"`
+	}
+
+	data := `annotation = "Test VM"
+unknown.key = "ignored"
+`
+
+	vm := new(VM)
+	err := Unmarshal([]byte(data), vm)
+	ok(t, err)
+	equals(t, "Test VM", vm.Annotation)
+	equals(t, "", vm.Internal)
+}
@@ -0,0 +1,220 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import "fmt"
+
+// Ethernet describes a single virtual network adapter.
+type Ethernet struct {
+	Present              bool   `vmx:"present"`
+	StartConnected       bool   `vmx:"startConnected"`
+	ConnectionType       string `vmx:"connectionType"`
+	VirtualDev           string `vmx:"virtualDev"`
+	AddressType          string `vmx:"addressType"`
+	Address              string `vmx:"address,omitempty"`
+	WakeOnPcktRcv        bool   `vmx:"wakeOnPcktRcv"`
+	LinkStatePropagation bool   `vmx:"linkStatePropagation.enable,omitempty"`
+}
+
+// Disk describes a single virtual disk or CD/DVD drive attached to a
+// controller unit.
+type Disk struct {
+	Present    bool   `vmx:"present"`
+	DeviceType string `vmx:"deviceType,omitempty"`
+	FileName   string `vmx:"fileName,omitempty"`
+}
+
+// SCSIController describes a virtual SCSI controller and the disks attached
+// to its units.
+type SCSIController struct {
+	Present    bool   `vmx:"present"`
+	VirtualDev string `vmx:"virtualDev"`
+	Disk       []Disk `vmx:":"`
+}
+
+// IDEController describes a virtual IDE controller and the disks or CD/DVD
+// drives attached to its units.
+type IDEController struct {
+	Disk []Disk `vmx:":"`
+}
+
+// NVMeController describes a virtual NVMe controller and the disks attached
+// to its units.
+type NVMeController struct {
+	Present bool   `vmx:"present"`
+	Disk    []Disk `vmx:":"`
+}
+
+// SerialPort describes a single virtual serial port.
+type SerialPort struct {
+	Present  bool   `vmx:"present"`
+	FileType string `vmx:"fileType,omitempty"`
+	FileName string `vmx:"fileName,omitempty"`
+}
+
+// USBController describes a virtual USB controller.
+type USBController struct {
+	Present bool `vmx:"present"`
+}
+
+// SoundCard describes the virtual sound device.
+type SoundCard struct {
+	Present    bool   `vmx:"present,omitempty"`
+	VirtualDev string `vmx:"virtualDev,omitempty"`
+	AutoDetect bool   `vmx:"autodetect,omitempty"`
+}
+
+// VirtualMachine is a high-level, editable model of a VMX file. It groups
+// the top-level VM settings with the device collections that back the
+// AddEthernet/AttachDisk family of helpers below, and it round-trips
+// through Marshal and Unmarshal like any other tagged struct.
+type VirtualMachine struct {
+	DisplayName string `vmx:"displayName"`
+	GuestOS     string `vmx:"guestOS"`
+	Memsize     uint   `vmx:"memsize"`
+	Numvcpus    uint   `vmx:"numvcpus"`
+
+	Ethernet []Ethernet       `vmx:"ethernet"`
+	SCSI     []SCSIController `vmx:"scsi"`
+	IDE      []IDEController  `vmx:"ide"`
+	NVMe     []NVMeController `vmx:"nvme"`
+	Serial   []SerialPort     `vmx:"serial"`
+	USB      []USBController  `vmx:"usb"`
+	Sound    SoundCard        `vmx:"sound"`
+}
+
+// AddEthernet appends a new, present, start-connected Ethernet adapter
+// using connectionType (e.g. "bridged", "nat", "hostonly") and returns its
+// index.
+func (vm *VirtualMachine) AddEthernet(connectionType string) int {
+	vm.Ethernet = append(vm.Ethernet, Ethernet{
+		Present:        true,
+		StartConnected: true,
+		ConnectionType: connectionType,
+		VirtualDev:     "e1000",
+		AddressType:    "generated",
+	})
+	return len(vm.Ethernet) - 1
+}
+
+// RemoveEthernet removes the Ethernet adapter at index, shifting the
+// adapters after it down by one slot.
+func (vm *VirtualMachine) RemoveEthernet(index int) error {
+	if index < 0 || index >= len(vm.Ethernet) {
+		return fmt.Errorf("vmx: no ethernet adapter at index %d", index)
+	}
+	vm.Ethernet = append(vm.Ethernet[:index], vm.Ethernet[index+1:]...)
+	return nil
+}
+
+// NextControllerKey returns the index a newly added controller of the
+// given kind ("scsi", "ide", or "nvme") would occupy.
+func (vm *VirtualMachine) NextControllerKey(kind string) (int, error) {
+	switch kind {
+	case "scsi":
+		return len(vm.SCSI), nil
+	case "ide":
+		return len(vm.IDE), nil
+	case "nvme":
+		return len(vm.NVMe), nil
+	default:
+		return 0, fmt.Errorf("vmx: unknown controller kind %q", kind)
+	}
+}
+
+// AddSCSIController appends a new SCSI controller using virtualDev (e.g.
+// "lsilogic", "pvscsi") and returns its index.
+func (vm *VirtualMachine) AddSCSIController(virtualDev string) int {
+	vm.SCSI = append(vm.SCSI, SCSIController{Present: true, VirtualDev: virtualDev})
+	return len(vm.SCSI) - 1
+}
+
+// AddIDEController appends a new IDE controller and returns its index.
+func (vm *VirtualMachine) AddIDEController() int {
+	vm.IDE = append(vm.IDE, IDEController{})
+	return len(vm.IDE) - 1
+}
+
+// AddNVMeController appends a new NVMe controller and returns its index.
+func (vm *VirtualMachine) AddNVMeController() int {
+	vm.NVMe = append(vm.NVMe, NVMeController{Present: true})
+	return len(vm.NVMe) - 1
+}
+
+// AttachDisk attaches a virtual disk backed by path to unit on the SCSI
+// controller at index controller, growing the controller's unit list as
+// needed.
+func (vm *VirtualMachine) AttachDisk(controller, unit int, path string) error {
+	if controller < 0 || controller >= len(vm.SCSI) {
+		return fmt.Errorf("vmx: no scsi controller at index %d", controller)
+	}
+
+	c := &vm.SCSI[controller]
+	for len(c.Disk) <= unit {
+		c.Disk = append(c.Disk, Disk{})
+	}
+	c.Disk[unit] = Disk{Present: true, DeviceType: "scsi-hardDisk", FileName: path}
+	return nil
+}
+
+// AttachIDEDisk attaches a virtual disk backed by path to unit on the IDE
+// controller at index controller, growing the controller's unit list as
+// needed.
+func (vm *VirtualMachine) AttachIDEDisk(controller, unit int, path string) error {
+	if controller < 0 || controller >= len(vm.IDE) {
+		return fmt.Errorf("vmx: no ide controller at index %d", controller)
+	}
+
+	c := &vm.IDE[controller]
+	for len(c.Disk) <= unit {
+		c.Disk = append(c.Disk, Disk{})
+	}
+	c.Disk[unit] = Disk{Present: true, DeviceType: "ata-hardDisk", FileName: path}
+	return nil
+}
+
+// AttachNVMeDisk attaches a virtual disk backed by path to unit on the
+// NVMe controller at index controller, growing the controller's unit list
+// as needed.
+func (vm *VirtualMachine) AttachNVMeDisk(controller, unit int, path string) error {
+	if controller < 0 || controller >= len(vm.NVMe) {
+		return fmt.Errorf("vmx: no nvme controller at index %d", controller)
+	}
+
+	c := &vm.NVMe[controller]
+	for len(c.Disk) <= unit {
+		c.Disk = append(c.Disk, Disk{})
+	}
+	c.Disk[unit] = Disk{Present: true, DeviceType: "nvme-hardDisk", FileName: path}
+	return nil
+}
+
+// FindDiskByFilename returns the controller kind ("scsi", "ide", or
+// "nvme"), controller index, and unit index of the first attached disk
+// whose file name equals path. ok is false if no disk matches.
+func (vm *VirtualMachine) FindDiskByFilename(path string) (kind string, controller, unit int, ok bool) {
+	for ci := range vm.SCSI {
+		for ui, d := range vm.SCSI[ci].Disk {
+			if d.Present && d.FileName == path {
+				return "scsi", ci, ui, true
+			}
+		}
+	}
+	for ci := range vm.IDE {
+		for ui, d := range vm.IDE[ci].Disk {
+			if d.Present && d.FileName == path {
+				return "ide", ci, ui, true
+			}
+		}
+	}
+	for ci := range vm.NVMe {
+		for ui, d := range vm.NVMe[ci].Disk {
+			if d.Present && d.FileName == path {
+				return "nvme", ci, ui, true
+			}
+		}
+	}
+	return "", 0, 0, false
+}
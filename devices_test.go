@@ -0,0 +1,195 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import "testing"
+
+func TestAddEthernet(t *testing.T) {
+	vm := new(VirtualMachine)
+
+	idx := vm.AddEthernet("nat")
+	equals(t, 0, idx)
+	equals(t, 1, len(vm.Ethernet))
+	equals(t, true, vm.Ethernet[0].Present)
+	equals(t, "nat", vm.Ethernet[0].ConnectionType)
+
+	idx = vm.AddEthernet("bridged")
+	equals(t, 1, idx)
+	equals(t, 2, len(vm.Ethernet))
+}
+
+func TestRemoveEthernet(t *testing.T) {
+	vm := new(VirtualMachine)
+	vm.AddEthernet("nat")
+	vm.AddEthernet("bridged")
+
+	err := vm.RemoveEthernet(0)
+	ok(t, err)
+	equals(t, 1, len(vm.Ethernet))
+	equals(t, "bridged", vm.Ethernet[0].ConnectionType)
+
+	err = vm.RemoveEthernet(5)
+	equals(t, "vmx: no ethernet adapter at index 5", err.Error())
+}
+
+func TestNextControllerKey(t *testing.T) {
+	vm := new(VirtualMachine)
+	vm.AddSCSIController("lsilogic")
+
+	key, err := vm.NextControllerKey("scsi")
+	ok(t, err)
+	equals(t, 1, key)
+
+	_, err = vm.NextControllerKey("sata")
+	equals(t, `vmx: unknown controller kind "sata"`, err.Error())
+}
+
+func TestAttachDiskAndFindDiskByFilename(t *testing.T) {
+	vm := new(VirtualMachine)
+	controller := vm.AddSCSIController("lsilogic")
+
+	err := vm.AttachDisk(controller, 0, "disk0.vmdk")
+	ok(t, err)
+
+	kind, ci, ui, found := vm.FindDiskByFilename("disk0.vmdk")
+	equals(t, true, found)
+	equals(t, "scsi", kind)
+	equals(t, controller, ci)
+	equals(t, 0, ui)
+
+	_, _, _, found = vm.FindDiskByFilename("missing.vmdk")
+	equals(t, false, found)
+
+	err = vm.AttachDisk(1, 0, "disk1.vmdk")
+	equals(t, "vmx: no scsi controller at index 1", err.Error())
+}
+
+func TestAttachIDEDiskAndFindDiskByFilename(t *testing.T) {
+	vm := new(VirtualMachine)
+	controller := vm.AddIDEController()
+
+	err := vm.AttachIDEDisk(controller, 0, "cdrom.iso")
+	ok(t, err)
+
+	kind, ci, ui, found := vm.FindDiskByFilename("cdrom.iso")
+	equals(t, true, found)
+	equals(t, "ide", kind)
+	equals(t, controller, ci)
+	equals(t, 0, ui)
+
+	err = vm.AttachIDEDisk(1, 0, "other.iso")
+	equals(t, "vmx: no ide controller at index 1", err.Error())
+}
+
+func TestAddNVMeControllerAndAttachNVMeDisk(t *testing.T) {
+	vm := new(VirtualMachine)
+	controller := vm.AddNVMeController()
+	equals(t, 0, controller)
+	equals(t, true, vm.NVMe[0].Present)
+
+	err := vm.AttachNVMeDisk(controller, 0, "nvme0.vmdk")
+	ok(t, err)
+
+	kind, ci, ui, found := vm.FindDiskByFilename("nvme0.vmdk")
+	equals(t, true, found)
+	equals(t, "nvme", kind)
+	equals(t, controller, ci)
+	equals(t, 0, ui)
+
+	err = vm.AttachNVMeDisk(1, 0, "other.vmdk")
+	equals(t, "vmx: no nvme controller at index 1", err.Error())
+}
+
+func TestVirtualMachineRoundTrip(t *testing.T) {
+	vm := new(VirtualMachine)
+	vm.DisplayName = "test"
+	vm.GuestOS = "other3xlinux-64"
+	vm.Memsize = 1024
+	vm.Numvcpus = 2
+
+	vm.AddEthernet("nat")
+
+	controller := vm.AddSCSIController("lsilogic")
+	ok(t, vm.AttachDisk(controller, 0, "disk0.vmdk"))
+	ok(t, vm.AttachDisk(controller, 1, "disk1.vmdk"))
+
+	data, err := Marshal(vm)
+	ok(t, err)
+
+	expected := `displayName = "test"
+guestOS = "other3xlinux-64"
+memsize = "1024"
+numvcpus = "2"
+ethernet0.present = "true"
+ethernet0.startConnected = "true"
+ethernet0.connectionType = "nat"
+ethernet0.virtualDev = "e1000"
+ethernet0.addressType = "generated"
+ethernet0.wakeOnPcktRcv = "false"
+scsi0.present = "true"
+scsi0.virtualDev = "lsilogic"
+scsi0:0.present = "true"
+scsi0:0.deviceType = "scsi-hardDisk"
+scsi0:0.fileName = "disk0.vmdk"
+scsi0:1.present = "true"
+scsi0:1.deviceType = "scsi-hardDisk"
+scsi0:1.fileName = "disk1.vmdk"
+`
+	equals(t, expected, string(data))
+
+	decoded := new(VirtualMachine)
+	ok(t, Unmarshal(data, decoded))
+	equals(t, vm, decoded)
+}
+
+func TestIDERoundTrip(t *testing.T) {
+	vm := new(VirtualMachine)
+	controller := vm.AddIDEController()
+	ok(t, vm.AttachIDEDisk(controller, 0, "cdrom.iso"))
+
+	data, err := Marshal(vm)
+	ok(t, err)
+
+	decoded := new(VirtualMachine)
+	ok(t, Unmarshal(data, decoded))
+	equals(t, vm, decoded)
+}
+
+func TestNVMeRoundTrip(t *testing.T) {
+	vm := new(VirtualMachine)
+	controller := vm.AddNVMeController()
+	ok(t, vm.AttachNVMeDisk(controller, 0, "nvme0.vmdk"))
+
+	data, err := Marshal(vm)
+	ok(t, err)
+
+	decoded := new(VirtualMachine)
+	ok(t, Unmarshal(data, decoded))
+	equals(t, vm, decoded)
+}
+
+func TestSerialRoundTrip(t *testing.T) {
+	vm := new(VirtualMachine)
+	vm.Serial = append(vm.Serial, SerialPort{Present: true, FileType: "file", FileName: "serial0.log"})
+
+	data, err := Marshal(vm)
+	ok(t, err)
+
+	decoded := new(VirtualMachine)
+	ok(t, Unmarshal(data, decoded))
+	equals(t, vm, decoded)
+}
+
+func TestUSBRoundTrip(t *testing.T) {
+	vm := new(VirtualMachine)
+	vm.USB = append(vm.USB, USBController{Present: true})
+
+	data, err := Marshal(vm)
+	ok(t, err)
+
+	decoded := new(VirtualMachine)
+	ok(t, Unmarshal(data, decoded))
+	equals(t, vm, decoded)
+}
@@ -0,0 +1,206 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package vmx implements encoding and decoding of VMware VMX files.
+package vmx
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Marshal returns the VMX encoding of v.
+//
+// Marshal traverses the value v, which must be a struct or a pointer to a
+// struct, and encodes each exported field tagged with a `vmx:"..."` struct
+// tag as a "key = \"value\"" line. Embedded struct fields are expanded using
+// a dotted key built from the outer and inner tag names, and slice-of-struct
+// fields are expanded into successive numeric suffixes (e.g. ethernet0,
+// ethernet1, ...). Fields tagged `vmx:"-"` are skipped, and fields tagged
+// with the `omitempty` option are omitted from the output when they hold
+// their zero value.
+func Marshal(v interface{}) ([]byte, error) {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("vmx: Marshal requires a struct, got %s", val.Kind())
+	}
+
+	var buf bytes.Buffer
+	if err := marshalStruct(&buf, "", val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalStruct writes the fields of val to buf, joining each field's tag
+// name to prefix with a dot when prefix is non-empty.
+func marshalStruct(buf *bytes.Buffer, prefix string, val reflect.Value) error {
+	t := val.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		rawTag := field.Tag.Get("vmx")
+		if rawTag == "" {
+			continue
+		}
+
+		name, omitempty, err := parseTag(`vmx:"` + rawTag + `"`)
+		if err != nil {
+			return err
+		}
+		if name == "-" {
+			continue
+		}
+
+		// A tag name of ":" marks a slice whose elements are addressed as
+		// controller units (e.g. scsi0:0, scsi0:1) rather than getting a
+		// name of their own; the index is appended to prefix with a colon
+		// instead of the usual dotted name.
+		unitIndexed := name == ":"
+
+		var key string
+		switch {
+		case unitIndexed:
+			key = prefix
+		case prefix != "":
+			key = prefix + "." + name
+		default:
+			key = name
+		}
+
+		fv := val.Field(i)
+
+		if m, ok := asMarshaler(fv); ok {
+			pairs, err := m.MarshalVMX(key)
+			if err != nil {
+				return err
+			}
+			writePairs(buf, pairs)
+			continue
+		}
+
+		switch fv.Kind() {
+		case reflect.Struct:
+			if err := marshalStruct(buf, key, fv); err != nil {
+				return err
+			}
+		case reflect.Slice:
+			sep := ""
+			if unitIndexed {
+				sep = ":"
+			}
+			for idx := 0; idx < fv.Len(); idx++ {
+				elemKey := fmt.Sprintf("%s%s%d", key, sep, idx)
+				elem := fv.Index(idx)
+				for elem.Kind() == reflect.Ptr {
+					elem = elem.Elem()
+				}
+				if elem.Kind() != reflect.Struct {
+					return fmt.Errorf("vmx: unsupported slice element kind %s for field %s", elem.Kind(), field.Name)
+				}
+				if err := marshalStruct(buf, elemKey, elem); err != nil {
+					return err
+				}
+			}
+		default:
+			if omitempty && isZero(fv) {
+				continue
+			}
+			s, err := formatValue(fv)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(buf, "%s = %q\n", key, s)
+		}
+	}
+	return nil
+}
+
+// writePairs writes pairs to buf as sorted "key = \"value\"" lines, so
+// that MarshalerVMX output is deterministic.
+func writePairs(buf *bytes.Buffer, pairs map[string]string) {
+	keys := make([]string, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(buf, "%s = %q\n", k, pairs[k])
+	}
+}
+
+// formatValue renders v, which must hold a bool, string, or integer kind,
+// as the string that is written between the quotes of a VMX value.
+func formatValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	default:
+		return "", fmt.Errorf("vmx: unsupported field kind %s", v.Kind())
+	}
+}
+
+// isZero reports whether v holds the zero value for its kind.
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String() == ""
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	default:
+		return false
+	}
+}
+
+// parseTag splits a raw `vmx:"..."` struct tag into its key name and
+// omitempty option.
+func parseTag(tag string) (name string, omitempty bool, err error) {
+	const prefix = "vmx:"
+	if !strings.HasPrefix(tag, prefix) {
+		return "", false, fmt.Errorf("Invalid tag: %s", tag)
+	}
+
+	rest := tag[len(prefix):]
+	if rest == "" {
+		return "", false, fmt.Errorf("Invalid tag: %s", tag)
+	}
+	if rest[0] != '"' {
+		return "", false, fmt.Errorf("Tag name has to be enclosed in double quotes: %s", tag)
+	}
+
+	content := rest[1:]
+	if idx := strings.IndexByte(content, '"'); idx >= 0 {
+		content = content[:idx]
+	}
+
+	parts := strings.Split(content, ",")
+	name = parts[0]
+	if name == "" {
+		return "", false, fmt.Errorf("Tag name is missing: %s", tag)
+	}
+
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+
+	return name, omitempty, nil
+}
@@ -0,0 +1,62 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import "reflect"
+
+// MarshalerVMX is implemented by types that encode themselves into one or
+// more VMX key/value pairs, instead of the single primitive value Marshal
+// would otherwise produce for their field.
+//
+// MarshalVMX is called with the key the field's own `vmx:"..."` tag maps
+// to (e.g. "ethernet0.address") and returns the full set of keys and
+// values that should be written in its place, letting one Go field expand
+// into several VMX lines: a MAC address type, for example, could return
+// both the address itself and an accompanying "...addressType" key.
+type MarshalerVMX interface {
+	MarshalVMX(key string) (map[string]string, error)
+}
+
+// UnmarshalerVMX is implemented by types that decode themselves from the
+// VMX key/value pairs produced by a matching MarshalerVMX.
+//
+// UnmarshalVMX is called with the key the field's own `vmx:"..."` tag maps
+// to and the full set of parsed VMX fields, so it can look up whatever
+// related keys it needs (e.g. both "...address" and "...addressType").
+type UnmarshalerVMX interface {
+	UnmarshalVMX(key string, fields map[string]string) error
+}
+
+// asMarshaler reports whether v, or a pointer to v if v is addressable,
+// implements MarshalerVMX.
+func asMarshaler(v reflect.Value) (MarshalerVMX, bool) {
+	if v.CanInterface() {
+		if m, ok := v.Interface().(MarshalerVMX); ok {
+			return m, true
+		}
+	}
+	if v.CanAddr() {
+		if m, ok := v.Addr().Interface().(MarshalerVMX); ok {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// asUnmarshaler reports whether a pointer to v, or v itself if it is
+// already a pointer, implements UnmarshalerVMX.
+func asUnmarshaler(v reflect.Value) (UnmarshalerVMX, bool) {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(UnmarshalerVMX); ok {
+			return u, true
+		}
+	}
+	if v.CanInterface() {
+		if u, ok := v.Interface().(UnmarshalerVMX); ok {
+			return u, true
+		}
+	}
+	return nil, false
+}
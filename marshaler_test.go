@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import (
+	"strings"
+	"testing"
+)
+
+// MACAddress is a MarshalerVMX/UnmarshalerVMX example: one Go field
+// expands into the "...address" and "...addressType" key pair VMware
+// expects for an Ethernet adapter's MAC address.
+type MACAddress struct {
+	Address string
+	Static  bool
+}
+
+func (m MACAddress) MarshalVMX(key string) (map[string]string, error) {
+	addressType := "generated"
+	if m.Static {
+		addressType = "static"
+	}
+	return map[string]string{
+		key: m.Address,
+		strings.TrimSuffix(key, "address") + "addressType": addressType,
+	}, nil
+}
+
+func (m *MACAddress) UnmarshalVMX(key string, fields map[string]string) error {
+	m.Address = fields[key]
+	m.Static = fields[strings.TrimSuffix(key, "address")+"addressType"] == "static"
+	return nil
+}
+
+func TestMarshalerVMX(t *testing.T) {
+	type VM struct {
+		Address MACAddress `vmx:"ethernet0.address"`
+	}
+
+	vm := VM{Address: MACAddress{Address: "00:0c:29:ab:cd:ef", Static: true}}
+
+	data, err := Marshal(&vm)
+	ok(t, err)
+
+	expected := `ethernet0.address = "00:0c:29:ab:cd:ef"
+ethernet0.addressType = "static"
+`
+	equals(t, expected, string(data))
+}
+
+func TestUnmarshalerVMX(t *testing.T) {
+	type VM struct {
+		Address MACAddress `vmx:"ethernet0.address"`
+	}
+
+	data := `ethernet0.address = "00:0c:29:ab:cd:ef"
+ethernet0.addressType = "generated"
+`
+
+	vm := new(VM)
+	err := Unmarshal([]byte(data), vm)
+	ok(t, err)
+
+	equals(t, "00:0c:29:ab:cd:ef", vm.Address.Address)
+	equals(t, false, vm.Address.Static)
+}
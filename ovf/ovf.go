@@ -0,0 +1,314 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package ovf bridges OVF/OVA descriptors and the VMX virtual machine
+// model in package vmx.
+package ovf
+
+import (
+	"archive/tar"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	vmx "github.com/eiwi1101/govmx"
+)
+
+// CIM_ResourceAllocationSettingData ResourceType values used by the items
+// this package understands. See DSP0004/DSP8023 for the full table.
+const (
+	resourceTypeIDEController  = 5
+	resourceTypeCPU            = 3
+	resourceTypeMemory         = 4
+	resourceTypeSCSIController = 6
+	resourceTypeEthernet       = 10
+	resourceTypeCDDrive        = 15
+	resourceTypeDisk           = 17
+)
+
+// Envelope is the root element of an OVF descriptor.
+type Envelope struct {
+	XMLName       xml.Name      `xml:"Envelope"`
+	VirtualSystem VirtualSystem `xml:"VirtualSystem"`
+}
+
+// VirtualSystem describes a single VM within an OVF envelope.
+type VirtualSystem struct {
+	ID                     string                 `xml:"id,attr"`
+	Name                   string                 `xml:"Name"`
+	VirtualHardwareSection VirtualHardwareSection `xml:"VirtualHardwareSection"`
+}
+
+// VirtualHardwareSection lists the virtual hardware items of a VirtualSystem.
+type VirtualHardwareSection struct {
+	Item []Item `xml:"Item"`
+}
+
+// Item is a single CIM_ResourceAllocationSettingData entry, e.g. a CPU,
+// memory allocation, disk, or network adapter.
+type Item struct {
+	InstanceID      string `xml:"InstanceID"`
+	Parent          string `xml:"Parent,omitempty"`
+	ResourceType    int    `xml:"ResourceType"`
+	ResourceSubType string `xml:"ResourceSubType,omitempty"`
+	ElementName     string `xml:"ElementName,omitempty"`
+	VirtualQuantity uint   `xml:"VirtualQuantity,omitempty"`
+	AddressOnParent string `xml:"AddressOnParent,omitempty"`
+	Connection      string `xml:"Connection,omitempty"`
+	HostResource    string `xml:"HostResource,omitempty"`
+}
+
+// controllerRef locates a controller added to a VirtualMachine by the kind
+// of bus it was added to ("scsi" or "ide") and its index on that bus.
+type controllerRef struct {
+	kind  string
+	index int
+}
+
+// Import reads an OVF descriptor from r and returns the VirtualMachine it
+// describes.
+//
+// CPU and memory items populate Numvcpus and Memsize; Ethernet items are
+// added via vmx.VirtualMachine.AddEthernet using their Connection as the
+// network name; SCSI and IDE controller items are added via
+// AddSCSIController/AddIDEController, and disk/CD-drive items whose
+// Parent references a controller's InstanceID are attached to it via
+// AttachDisk/AttachIDEDisk using the file referenced by HostResource.
+func Import(r io.Reader) (*vmx.VirtualMachine, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var env Envelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("ovf: invalid descriptor: %s", err)
+	}
+
+	vm := new(vmx.VirtualMachine)
+	vm.DisplayName = env.VirtualSystem.Name
+
+	// Controllers must be added before the disks attached to them, so walk
+	// the items in two passes: controllers and simple scalars first, then
+	// devices that reference a controller's InstanceID as Parent.
+	controllers := make(map[string]controllerRef)
+	var disks []Item
+
+	for _, item := range env.VirtualSystem.VirtualHardwareSection.Item {
+		switch item.ResourceType {
+		case resourceTypeCPU:
+			vm.Numvcpus = item.VirtualQuantity
+		case resourceTypeMemory:
+			vm.Memsize = item.VirtualQuantity
+		case resourceTypeEthernet:
+			idx := vm.AddEthernet(networkConnectionType(item.Connection))
+			vm.Ethernet[idx].VirtualDev = item.ResourceSubType
+		case resourceTypeSCSIController:
+			idx := vm.AddSCSIController(item.ResourceSubType)
+			controllers[item.InstanceID] = controllerRef{"scsi", idx}
+		case resourceTypeIDEController:
+			idx := vm.AddIDEController()
+			controllers[item.InstanceID] = controllerRef{"ide", idx}
+		case resourceTypeDisk, resourceTypeCDDrive:
+			disks = append(disks, item)
+		}
+	}
+
+	for _, item := range disks {
+		ref, ok := controllers[item.Parent]
+		if !ok {
+			return nil, fmt.Errorf("ovf: disk item %q references unknown controller %q", item.InstanceID, item.Parent)
+		}
+
+		unit := unitFromAddress(item.AddressOnParent)
+		path := hostResourcePath(item.HostResource)
+
+		switch ref.kind {
+		case "scsi":
+			err = vm.AttachDisk(ref.index, unit, path)
+		case "ide":
+			err = vm.AttachIDEDisk(ref.index, unit, path)
+		default:
+			err = fmt.Errorf("ovf: unsupported controller kind %q for disk item %q", ref.kind, item.InstanceID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if item.ResourceType == resourceTypeCDDrive {
+			switch ref.kind {
+			case "scsi":
+				vm.SCSI[ref.index].Disk[unit].DeviceType = "cdrom-image"
+			case "ide":
+				vm.IDE[ref.index].Disk[unit].DeviceType = "cdrom-image"
+			}
+		}
+	}
+
+	return vm, nil
+}
+
+// ImportOVA reads an OVA archive from r, locates its .ovf descriptor entry,
+// and returns the VirtualMachine it describes.
+func ImportOVA(r io.Reader) (*vmx.VirtualMachine, error) {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("ovf: OVA archive has no .ovf descriptor")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(filepath.Ext(hdr.Name), ".ovf") {
+			return Import(tr)
+		}
+	}
+}
+
+// Export renders vm as a minimal OVF-style descriptor whose VirtualSystem
+// id is name. The XML carries no OVF or CIM namespace declarations and has
+// no References, DiskSection or NetworkSection; each disk's HostResource is
+// "ovf:/disk/" followed by its file name.
+//
+// Export covers the same device kinds Import understands (CPU, memory,
+// Ethernet, SCSI and IDE controllers with their attached disks/CD-drives).
+// vm.NVMe, vm.Serial, vm.USB, and vm.Sound have no OVF representation yet;
+// rather than silently drop them, Export refuses to export a VM that uses
+// any of them.
+func Export(vm *vmx.VirtualMachine, name string) ([]byte, error) {
+	if len(vm.NVMe) > 0 {
+		return nil, fmt.Errorf("ovf: Export does not yet support NVMe controllers")
+	}
+	if len(vm.Serial) > 0 {
+		return nil, fmt.Errorf("ovf: Export does not yet support serial ports")
+	}
+	if len(vm.USB) > 0 {
+		return nil, fmt.Errorf("ovf: Export does not yet support USB controllers")
+	}
+	if vm.Sound.Present {
+		return nil, fmt.Errorf("ovf: Export does not yet support sound devices")
+	}
+
+	env := Envelope{
+		VirtualSystem: VirtualSystem{
+			ID:   name,
+			Name: vm.DisplayName,
+		},
+	}
+	items := &env.VirtualSystem.VirtualHardwareSection.Item
+
+	nextID := 0
+	newID := func() string {
+		id := fmt.Sprintf("%d", nextID)
+		nextID++
+		return id
+	}
+
+	*items = append(*items, Item{InstanceID: newID(), ResourceType: resourceTypeCPU, VirtualQuantity: vm.Numvcpus})
+	*items = append(*items, Item{InstanceID: newID(), ResourceType: resourceTypeMemory, VirtualQuantity: vm.Memsize})
+
+	for _, eth := range vm.Ethernet {
+		*items = append(*items, Item{
+			InstanceID:      newID(),
+			ResourceType:    resourceTypeEthernet,
+			ResourceSubType: eth.VirtualDev,
+			Connection:      eth.ConnectionType,
+		})
+	}
+
+	for _, controller := range vm.SCSI {
+		controllerID := newID()
+		*items = append(*items, Item{
+			InstanceID:      controllerID,
+			ResourceType:    resourceTypeSCSIController,
+			ResourceSubType: controller.VirtualDev,
+		})
+		for unit, disk := range controller.Disk {
+			if !disk.Present {
+				continue
+			}
+			resourceType := resourceTypeDisk
+			if disk.DeviceType == "cdrom-image" {
+				resourceType = resourceTypeCDDrive
+			}
+			*items = append(*items, Item{
+				InstanceID:      newID(),
+				Parent:          controllerID,
+				ResourceType:    resourceType,
+				AddressOnParent: fmt.Sprintf("%d", unit),
+				HostResource:    fmt.Sprintf("ovf:/disk/%s", disk.FileName),
+			})
+		}
+	}
+
+	for _, controller := range vm.IDE {
+		controllerID := newID()
+		*items = append(*items, Item{
+			InstanceID:   controllerID,
+			ResourceType: resourceTypeIDEController,
+		})
+		for unit, disk := range controller.Disk {
+			if !disk.Present {
+				continue
+			}
+			resourceType := resourceTypeDisk
+			if disk.DeviceType == "cdrom-image" {
+				resourceType = resourceTypeCDDrive
+			}
+			*items = append(*items, Item{
+				InstanceID:      newID(),
+				Parent:          controllerID,
+				ResourceType:    resourceType,
+				AddressOnParent: fmt.Sprintf("%d", unit),
+				HostResource:    fmt.Sprintf("ovf:/disk/%s", disk.FileName),
+			})
+		}
+	}
+
+	out, err := xml.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// networkConnectionType maps an OVF network name to the VMX connectionType
+// value closest to it; unrecognized names map to "custom", which VMware
+// treats as a named host-only/bridged network. The network name itself is
+// not kept.
+func networkConnectionType(network string) string {
+	switch strings.ToLower(network) {
+	case "", "nat":
+		return "nat"
+	case "bridged":
+		return "bridged"
+	case "hostonly", "host-only":
+		return "hostonly"
+	default:
+		return "custom"
+	}
+}
+
+// unitFromAddress parses an OVF AddressOnParent value into a controller
+// unit index, defaulting to 0 if it is missing or malformed.
+func unitFromAddress(address string) int {
+	var unit int
+	if _, err := fmt.Sscanf(address, "%d", &unit); err != nil {
+		return 0
+	}
+	return unit
+}
+
+// hostResourcePath extracts the file path portion of an OVF HostResource
+// reference such as "ovf:/disk/disk1.vmdk".
+func hostResourcePath(hostResource string) string {
+	if idx := strings.LastIndex(hostResource, "/"); idx >= 0 {
+		return hostResource[idx+1:]
+	}
+	return hostResource
+}
@@ -0,0 +1,192 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package ovf
+
+import (
+	"strings"
+	"testing"
+)
+
+const descriptor = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope>
+  <VirtualSystem id="vm">
+    <Name>test</Name>
+    <VirtualHardwareSection>
+      <Item>
+        <InstanceID>1</InstanceID>
+        <ResourceType>3</ResourceType>
+        <VirtualQuantity>2</VirtualQuantity>
+      </Item>
+      <Item>
+        <InstanceID>2</InstanceID>
+        <ResourceType>4</ResourceType>
+        <VirtualQuantity>1024</VirtualQuantity>
+      </Item>
+      <Item>
+        <InstanceID>3</InstanceID>
+        <ResourceType>10</ResourceType>
+        <ResourceSubType>E1000</ResourceSubType>
+        <Connection>bridged</Connection>
+      </Item>
+      <Item>
+        <InstanceID>4</InstanceID>
+        <ResourceType>6</ResourceType>
+        <ResourceSubType>lsilogic</ResourceSubType>
+      </Item>
+      <Item>
+        <InstanceID>5</InstanceID>
+        <Parent>4</Parent>
+        <ResourceType>17</ResourceType>
+        <AddressOnParent>0</AddressOnParent>
+        <HostResource>ovf:/disk/disk1.vmdk</HostResource>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+func TestImport(t *testing.T) {
+	vm, err := Import(strings.NewReader(descriptor))
+	if err != nil {
+		t.Fatalf("Import: %s", err)
+	}
+
+	if vm.DisplayName != "test" {
+		t.Errorf("DisplayName = %q, want %q", vm.DisplayName, "test")
+	}
+	if vm.Numvcpus != 2 {
+		t.Errorf("Numvcpus = %d, want 2", vm.Numvcpus)
+	}
+	if vm.Memsize != 1024 {
+		t.Errorf("Memsize = %d, want 1024", vm.Memsize)
+	}
+	if len(vm.Ethernet) != 1 || vm.Ethernet[0].ConnectionType != "bridged" {
+		t.Fatalf("Ethernet = %+v, want one bridged adapter", vm.Ethernet)
+	}
+	if len(vm.SCSI) != 1 || vm.SCSI[0].VirtualDev != "lsilogic" {
+		t.Fatalf("SCSI = %+v, want one lsilogic controller", vm.SCSI)
+	}
+	if len(vm.SCSI[0].Disk) != 1 || vm.SCSI[0].Disk[0].FileName != "disk1.vmdk" {
+		t.Fatalf("Disk = %+v, want unit 0 attached to disk1.vmdk", vm.SCSI[0].Disk)
+	}
+}
+
+const ideDescriptor = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope>
+  <VirtualSystem id="vm">
+    <Name>test</Name>
+    <VirtualHardwareSection>
+      <Item>
+        <InstanceID>1</InstanceID>
+        <ResourceType>3</ResourceType>
+        <VirtualQuantity>1</VirtualQuantity>
+      </Item>
+      <Item>
+        <InstanceID>2</InstanceID>
+        <ResourceType>4</ResourceType>
+        <VirtualQuantity>512</VirtualQuantity>
+      </Item>
+      <Item>
+        <InstanceID>4</InstanceID>
+        <ResourceType>5</ResourceType>
+      </Item>
+      <Item>
+        <InstanceID>5</InstanceID>
+        <Parent>4</Parent>
+        <ResourceType>15</ResourceType>
+        <AddressOnParent>0</AddressOnParent>
+        <HostResource>ovf:/disk/install.iso</HostResource>
+      </Item>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+func TestImportIDEControllerWithCDDrive(t *testing.T) {
+	vm, err := Import(strings.NewReader(ideDescriptor))
+	if err != nil {
+		t.Fatalf("Import: %s", err)
+	}
+
+	if len(vm.IDE) != 1 {
+		t.Fatalf("IDE = %+v, want one controller", vm.IDE)
+	}
+	if len(vm.IDE[0].Disk) != 1 || vm.IDE[0].Disk[0].FileName != "install.iso" {
+		t.Fatalf("Disk = %+v, want unit 0 attached to install.iso", vm.IDE[0].Disk)
+	}
+	if vm.IDE[0].Disk[0].DeviceType != "cdrom-image" {
+		t.Errorf("DeviceType = %q, want %q", vm.IDE[0].Disk[0].DeviceType, "cdrom-image")
+	}
+}
+
+func TestExportIDERoundTrip(t *testing.T) {
+	vm, err := Import(strings.NewReader(ideDescriptor))
+	if err != nil {
+		t.Fatalf("Import: %s", err)
+	}
+
+	data, err := Export(vm, "vm")
+	if err != nil {
+		t.Fatalf("Export: %s", err)
+	}
+
+	roundTripped, err := Import(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Import(Export(vm)): %s", err)
+	}
+
+	if len(roundTripped.IDE) != 1 || len(roundTripped.IDE[0].Disk) != 1 ||
+		roundTripped.IDE[0].Disk[0].FileName != "install.iso" ||
+		roundTripped.IDE[0].Disk[0].DeviceType != "cdrom-image" {
+		t.Fatalf("IDE CD-ROM did not round-trip: got %+v", roundTripped.IDE)
+	}
+}
+
+func TestExportRejectsUnsupportedDevices(t *testing.T) {
+	vm, err := Import(strings.NewReader(descriptor))
+	if err != nil {
+		t.Fatalf("Import: %s", err)
+	}
+	vm.AddNVMeController()
+
+	if _, err := Export(vm, "vm"); err == nil {
+		t.Fatal("Export: expected error for VM with an NVMe controller, got nil")
+	}
+}
+
+func TestImportUnknownParent(t *testing.T) {
+	bad := strings.Replace(descriptor, "<Parent>4</Parent>", "<Parent>99</Parent>", 1)
+	if _, err := Import(strings.NewReader(bad)); err == nil {
+		t.Fatal("Import: expected error for disk referencing unknown controller, got nil")
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	vm, err := Import(strings.NewReader(descriptor))
+	if err != nil {
+		t.Fatalf("Import: %s", err)
+	}
+
+	data, err := Export(vm, "vm")
+	if err != nil {
+		t.Fatalf("Export: %s", err)
+	}
+
+	roundTripped, err := Import(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Import(Export(vm)): %s", err)
+	}
+
+	if roundTripped.DisplayName != vm.DisplayName {
+		t.Errorf("DisplayName = %q, want %q", roundTripped.DisplayName, vm.DisplayName)
+	}
+	if roundTripped.Numvcpus != vm.Numvcpus || roundTripped.Memsize != vm.Memsize {
+		t.Errorf("CPU/memory did not round-trip: got %+v, want %+v", roundTripped, vm)
+	}
+	if len(roundTripped.SCSI) != 1 || len(roundTripped.SCSI[0].Disk) != 1 ||
+		roundTripped.SCSI[0].Disk[0].FileName != "disk1.vmdk" {
+		t.Fatalf("disk did not round-trip: got %+v", roundTripped.SCSI)
+	}
+}
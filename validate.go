@@ -0,0 +1,337 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity classifies how serious a ValidationError is: SeverityError
+// means the VMX is invalid and should be rejected, SeverityWarning means
+// the value is merely unrecognized or unusual.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// ValidationError describes one way in which a marshaled VMX key/value
+// pair fails to conform to its declared virtualHW.version schema.
+type ValidationError struct {
+	Key      string
+	Message  string
+	Severity Severity
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", e.Severity, e.Key, e.Message)
+}
+
+// ValidationErrors is the result of Validate: every schema violation
+// found, annotated with a Severity so callers can tell a hard rejection
+// from an advisory warning without matching on Message text.
+type ValidationErrors []ValidationError
+
+// Errors returns the subset of errs at SeverityError.
+func (errs ValidationErrors) Errors() ValidationErrors {
+	return errs.filter(SeverityError)
+}
+
+// Warnings returns the subset of errs at SeverityWarning.
+func (errs ValidationErrors) Warnings() ValidationErrors {
+	return errs.filter(SeverityWarning)
+}
+
+func (errs ValidationErrors) filter(severity Severity) ValidationErrors {
+	var out ValidationErrors
+	for _, e := range errs {
+		if e.Severity == severity {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// KeyValidator checks the value of a key that matched its associated
+// pattern in a HardwareVersionRules, returning a message describing the
+// problem and its severity, or "" if the value is acceptable.
+type KeyValidator func(key, value string) (message string, severity Severity)
+
+type keyRule struct {
+	pattern   *regexp.Regexp
+	validator KeyValidator
+}
+
+// HardwareVersionRules is the set of keys, and optional value checks,
+// that are valid for a single virtualHW.version.
+type HardwareVersionRules struct {
+	rules []keyRule
+}
+
+// NewHardwareVersionRules returns an empty rule set ready for Allow calls.
+func NewHardwareVersionRules() *HardwareVersionRules {
+	return &HardwareVersionRules{}
+}
+
+// Allow registers pattern, a regular expression written without ^ and $
+// anchors that must match a produced VMX key in full, as valid for this
+// hardware version. validator, if non-nil, is additionally run against the
+// value of a key that matches pattern. A key is checked only against the
+// first registered pattern it matches.
+func (r *HardwareVersionRules) Allow(pattern string, validator KeyValidator) {
+	r.rules = append(r.rules, keyRule{regexp.MustCompile("^" + pattern + "$"), validator})
+}
+
+func (r *HardwareVersionRules) match(key string) *keyRule {
+	for i := range r.rules {
+		if r.rules[i].pattern.MatchString(key) {
+			return &r.rules[i]
+		}
+	}
+	return nil
+}
+
+// hardwareVersions maps virtualHW.version to the rules that apply to it.
+// It is seeded with a baseline table by init and can be extended at
+// runtime with RegisterHardwareVersion.
+var hardwareVersions = map[uint8]*HardwareVersionRules{}
+
+// RegisterHardwareVersion installs (or replaces) the rules used to
+// validate the given virtualHW.version, so callers can extend the
+// built-in table as VMware ships new hardware versions.
+func RegisterHardwareVersion(version uint8, rules *HardwareVersionRules) {
+	hardwareVersions[version] = rules
+}
+
+func init() {
+	base := func() *HardwareVersionRules {
+		r := NewHardwareVersionRules()
+		r.Allow(`virtualHW\.version`, nil)
+		r.Allow(`virtualHW\.productCompatibility`, nil)
+		r.Allow(`\.encoding`, nil)
+		r.Allow(`annotation`, nil)
+		r.Allow(`displayName`, nil)
+		r.Allow(`guestOS`, validateGuestOS)
+		r.Allow(`memsize`, validateMemsize)
+		r.Allow(`numvcpus`, nil)
+		r.Allow(`mem\.hotadd`, nil)
+		r.Allow(`msg\.autoAnswer`, nil)
+		r.Allow(`ethernet\d+\..*`, nil)
+		r.Allow(`scsi\d+(:\d+)?\..*`, nil)
+		r.Allow(`ide\d+(:\d+)?\..*`, nil)
+		r.Allow(`usb\d*\..*`, nil)
+		r.Allow(`serial\d+\..*`, nil)
+		r.Allow(`sound\..*`, nil)
+		return r
+	}
+
+	// NVMe controllers were introduced with hardware version 13.
+	for _, v := range []uint8{9, 10, 11, 12} {
+		RegisterHardwareVersion(v, base())
+	}
+	for _, v := range []uint8{13, 14, 15, 16, 17, 18, 19} {
+		r := base()
+		r.Allow(`nvme\d+(:\d+)?\..*`, nil)
+		RegisterHardwareVersion(v, r)
+	}
+}
+
+// knownGuestOS lists the guestOS identifiers Validate recognizes; it is
+// intentionally a small, representative sample rather than VMware's full
+// list, so an unrecognized value only produces a warning, not an error.
+var knownGuestOS = map[string]bool{
+	"other3xLinux-64": true,
+	"otherLinux-64":   true,
+	"otherGuest":      true,
+	"otherGuest64":    true,
+	"ubuntu-64":       true,
+	"centos7-64":      true,
+	"windows9-64":     true,
+	"windows9srv-64":  true,
+}
+
+func validateGuestOS(key, value string) (string, Severity) {
+	if !knownGuestOS[value] {
+		return fmt.Sprintf("unrecognized guestOS value %q", value), SeverityWarning
+	}
+	return "", SeverityError
+}
+
+func validateMemsize(key, value string) (string, Severity) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Sprintf("memsize %q is not a number", value), SeverityError
+	}
+	if n <= 0 || n%4 != 0 {
+		return fmt.Sprintf("memsize %d must be a positive multiple of 4", n), SeverityError
+	}
+	return "", SeverityError
+}
+
+// controllerUnitPattern extracts the controller kind, index, and unit from
+// a colon-addressed device key such as "scsi0:7.present".
+var controllerUnitPattern = regexp.MustCompile(`^(scsi|ide|nvme)(\d+):(\d+)\.`)
+
+// maxUnitForController returns the highest unit index VMware allows on a
+// controller of the given kind, or -1 if kind is unknown.
+func maxUnitForController(kind string) int {
+	switch kind {
+	case "scsi":
+		return 15
+	case "ide":
+		return 1
+	case "nvme":
+		return 14
+	default:
+		return -1
+	}
+}
+
+// checkControllerUnits reports unit indices that fall outside the range
+// VMware allows for their controller kind, including SCSI unit 7, which is
+// reserved for the controller itself.
+func checkControllerUnits(keys []string) []ValidationError {
+	var errs []ValidationError
+	reported := make(map[string]bool)
+
+	for _, key := range keys {
+		m := controllerUnitPattern.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		kind, index, unitStr := m[1], m[2], m[3]
+		unit, err := strconv.Atoi(unitStr)
+		if err != nil {
+			continue
+		}
+
+		slot := kind + index + ":" + unitStr
+		if reported[slot] {
+			continue
+		}
+
+		switch {
+		case kind == "scsi" && unit == 7:
+			errs = append(errs, ValidationError{Key: key, Message: "scsi unit 7 is reserved for the controller itself"})
+			reported[slot] = true
+		case maxUnitForController(kind) >= 0 && unit > maxUnitForController(kind):
+			errs = append(errs, ValidationError{Key: key, Message: fmt.Sprintf("%s unit %d exceeds the maximum supported unit %d", kind, unit, maxUnitForController(kind))})
+			reported[slot] = true
+		}
+	}
+
+	return errs
+}
+
+// duplicateKeys returns the VMX keys that appear more than once in data,
+// one entry per offending key. tokenize collapses repeated keys into a
+// single map entry, so this scans Marshal's raw, ordered output directly;
+// it is how Validate catches two devices colliding on the same
+// controller/unit slot (or any other tag collision), which a
+// map[string]string can never reveal.
+func duplicateKeys(data []byte) []string {
+	seen := make(map[string]bool)
+	reported := make(map[string]bool)
+	var dups []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:idx])
+
+		if seen[key] {
+			if !reported[key] {
+				dups = append(dups, key)
+				reported[key] = true
+			}
+			continue
+		}
+		seen[key] = true
+	}
+
+	return dups
+}
+
+// Validate encodes v with Marshal and checks the resulting VMX key set
+// against the built-in schema table for its virtualHW.version, returning
+// every violation found, each tagged with a Severity. A nil result means
+// v is schema-valid. Use ValidationErrors.Errors to find violations that
+// should block using v, and ValidationErrors.Warnings for advisory ones.
+func Validate(v interface{}) ValidationErrors {
+	data, err := Marshal(v)
+	if err != nil {
+		return ValidationErrors{{Message: err.Error()}}
+	}
+
+	fields, err := tokenize(data)
+	if err != nil {
+		return ValidationErrors{{Message: err.Error()}}
+	}
+
+	versionStr, present := fields["virtualHW.version"]
+	if !present {
+		return ValidationErrors{{Key: "virtualHW.version", Message: "is required"}}
+	}
+
+	version, err := strconv.ParseUint(versionStr, 10, 8)
+	if err != nil {
+		return ValidationErrors{{Key: "virtualHW.version", Message: fmt.Sprintf("invalid value %q", versionStr)}}
+	}
+
+	rules, known := hardwareVersions[uint8(version)]
+	if !known {
+		return ValidationErrors{{Key: "virtualHW.version", Message: fmt.Sprintf("unsupported hardware version %d", version)}}
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs ValidationErrors
+	for _, key := range keys {
+		rule := rules.match(key)
+		if rule == nil {
+			errs = append(errs, ValidationError{Key: key, Message: fmt.Sprintf("not a valid key for hardware version %d", version)})
+			continue
+		}
+		if rule.validator != nil {
+			if msg, severity := rule.validator(key, fields[key]); msg != "" {
+				errs = append(errs, ValidationError{Key: key, Message: msg, Severity: severity})
+			}
+		}
+	}
+
+	for _, e := range checkControllerUnits(keys) {
+		errs = append(errs, e)
+	}
+	for _, key := range duplicateKeys(data) {
+		errs = append(errs, ValidationError{Key: key, Message: "key is set more than once"})
+	}
+
+	return errs
+}
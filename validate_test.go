@@ -0,0 +1,138 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package vmx
+
+import "testing"
+
+func TestValidateOK(t *testing.T) {
+	type VM struct {
+		Hwversion   uint8  `vmx:"virtualHW.version"`
+		DisplayName string `vmx:"displayName"`
+		GuestOS     string `vmx:"guestOS"`
+		Memsize     uint   `vmx:"memsize"`
+		Numvcpus    uint   `vmx:"numvcpus"`
+	}
+	vm := &VM{Hwversion: 10, DisplayName: "test", GuestOS: "ubuntu-64", Memsize: 2048, Numvcpus: 2}
+
+	errs := Validate(vm)
+	equals(t, 0, len(errs))
+}
+
+func TestValidateUnknownHardwareVersion(t *testing.T) {
+	type VM struct {
+		Hwversion uint8 `vmx:"virtualHW.version"`
+	}
+	errs := Validate(&VM{Hwversion: 250})
+	equals(t, 1, len(errs))
+	equals(t, "virtualHW.version", errs[0].Key)
+}
+
+func TestValidateRejectsNVMeOnOldHardware(t *testing.T) {
+	type VM struct {
+		Hwversion uint8            `vmx:"virtualHW.version"`
+		NVMe      []NVMeController `vmx:"nvme"`
+	}
+	vm := &VM{Hwversion: 10, NVMe: []NVMeController{{Present: true}}}
+
+	errs := Validate(vm)
+	found := false
+	for _, e := range errs {
+		if e.Key == "nvme0.present" {
+			found = true
+		}
+	}
+	equals(t, true, found)
+}
+
+func TestValidateMemsizeMultipleOfFour(t *testing.T) {
+	type VM struct {
+		Hwversion uint8 `vmx:"virtualHW.version"`
+		Memsize   uint  `vmx:"memsize"`
+	}
+	errs := Validate(&VM{Hwversion: 10, Memsize: 1023})
+	equals(t, 1, len(errs))
+	equals(t, "memsize", errs[0].Key)
+}
+
+func TestValidateWarnsOnUnknownGuestOS(t *testing.T) {
+	type VM struct {
+		Hwversion uint8  `vmx:"virtualHW.version"`
+		GuestOS   string `vmx:"guestOS"`
+	}
+	errs := Validate(&VM{Hwversion: 10, GuestOS: "madeUpOS-64"})
+	equals(t, 1, len(errs))
+	equals(t, "guestOS", errs[0].Key)
+	equals(t, SeverityWarning, errs[0].Severity)
+	equals(t, 0, len(errs.Errors()))
+	equals(t, 1, len(errs.Warnings()))
+}
+
+func TestValidateSeverityDistinguishesErrorsFromWarnings(t *testing.T) {
+	type VM struct {
+		Hwversion uint8  `vmx:"virtualHW.version"`
+		GuestOS   string `vmx:"guestOS"`
+		Memsize   uint   `vmx:"memsize"`
+	}
+	errs := Validate(&VM{Hwversion: 10, GuestOS: "madeUpOS-64", Memsize: 1023})
+	equals(t, 2, len(errs))
+	equals(t, 1, len(errs.Warnings()))
+	equals(t, 1, len(errs.Errors()))
+	equals(t, "memsize", errs.Errors()[0].Key)
+	equals(t, "guestOS", errs.Warnings()[0].Key)
+}
+
+func TestValidateDetectsDuplicateKey(t *testing.T) {
+	type VM struct {
+		Hwversion uint8 `vmx:"virtualHW.version"`
+		Memsize   uint  `vmx:"memsize"`
+		MemAlias  uint  `vmx:"memsize"`
+	}
+	errs := Validate(&VM{Hwversion: 10, Memsize: 1024, MemAlias: 1024})
+
+	found := false
+	for _, e := range errs {
+		if e.Key == "memsize" && e.Message == "key is set more than once" {
+			found = true
+		}
+	}
+	equals(t, true, found)
+}
+
+func TestValidateSCSIUnitSeven(t *testing.T) {
+	type VM struct {
+		Hwversion uint8            `vmx:"virtualHW.version"`
+		SCSI      []SCSIController `vmx:"scsi"`
+	}
+	vm := new(VM)
+	vm.Hwversion = 10
+	vm.SCSI = []SCSIController{{Present: true}}
+	for i := 0; i < 8; i++ {
+		vm.SCSI[0].Disk = append(vm.SCSI[0].Disk, Disk{})
+	}
+	vm.SCSI[0].Disk[7] = Disk{Present: true, FileName: "disk.vmdk"}
+
+	errs := Validate(vm)
+	found := false
+	for _, e := range errs {
+		if e.Message == "scsi unit 7 is reserved for the controller itself" {
+			found = true
+		}
+	}
+	equals(t, true, found)
+}
+
+func TestRegisterHardwareVersion(t *testing.T) {
+	rules := NewHardwareVersionRules()
+	rules.Allow(`virtualHW\.version`, nil)
+	rules.Allow(`custom\.field`, nil)
+	RegisterHardwareVersion(200, rules)
+
+	type VM struct {
+		Hwversion   uint8  `vmx:"virtualHW.version"`
+		CustomField string `vmx:"custom.field"`
+	}
+	errs := Validate(&VM{Hwversion: 200, CustomField: "ok"})
+	equals(t, 0, len(errs))
+}